@@ -0,0 +1,197 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+// BlasEngine is the set of low-level linear algebra kernels that gomat's
+// Level-3 operations (Dot) and Level-1 operations (Add, Sub, Scale)
+// delegate to. gomat ships with goEngine, a dependency-free pure-Go
+// implementation, and is used by default. Callers that need more
+// performance on large matrices can swap in a hardware-accelerated
+// implementation (e.g. a cgo binding to a netlib-compatible BLAS) via
+// Register, without changing any call sites.
+type BlasEngine interface {
+	// Dgemm computes c = alpha*a*b + beta*c, where a is m x k, b is
+	// k x n and c is m x n, all stored in row-major order with the
+	// given strides (lda, ldb, ldc are the number of elements between
+	// the start of consecutive rows).
+	Dgemm(m, n, k int, alpha float64, a []float64, lda int, b []float64, ldb int, beta float64, c []float64, ldc int)
+
+	// Daxpy computes y = alpha*x + y.
+	Daxpy(n int, alpha float64, x, y []float64)
+
+	// Dscal computes x = alpha*x.
+	Dscal(n int, alpha float64, x []float64)
+}
+
+// engine is the active BlasEngine used by the package-level matrix
+// operations. It defaults to the pure-Go implementation.
+var engine BlasEngine = goEngine{}
+
+// Register swaps the active BlasEngine used by package-level matrix
+// operations such as Dot, Add, Sub and Scale. It is intended to be
+// called once during program initialisation; it is not safe to call
+// Register concurrently with other gomat operations.
+func Register(e BlasEngine) {
+	if e == nil {
+		panic("gomat: nil BlasEngine")
+	}
+	engine = e
+}
+
+// goEngine is the default BlasEngine, implemented with plain Go loops
+// and no external dependencies.
+type goEngine struct{}
+
+// blockSize is the tile edge used by the blocked Dgemm kernel, chosen
+// so that an aPanel/bPanel/cTile triple (3 * blockSize^2 float64s)
+// comfortably fits in L1/L2 cache.
+const blockSize = 64
+
+// blockedGemmThreshold is the m*n*k work below which Dgemm falls back
+// to the naive transpose-and-dot path: packing panels has fixed
+// overhead that isn't worth paying for small matrices.
+const blockedGemmThreshold = blockSize * blockSize * blockSize
+
+// Dgemm computes c = alpha*a*b + beta*c. Small problems go through a
+// naive transpose-and-dot path; larger ones are tiled into
+// blockSize x blockSize panels that are packed into contiguous scratch
+// so the inner kernel is a stride-1 dot product, keeping each tile's
+// working set resident in cache. When SetParallel has raised the
+// worker count, rows of the output are additionally partitioned across
+// goroutines; each worker owns disjoint output rows, so no locking is
+// needed. The parallel split is only worth dispatching once the
+// problem is already large enough to take the blocked path - below
+// that, goroutine overhead would dwarf the naive path's own cost.
+func (goEngine) Dgemm(m, n, k int, alpha float64, a []float64, lda int, b []float64, ldb int, beta float64, c []float64, ldc int) {
+	work := 0
+	if m*n*k >= blockedGemmThreshold {
+		work = m * n * k
+	}
+	parallelChunks(m, work, func(start, end int) {
+		dgemmDispatch(end-start, n, k, alpha, a[start*lda:], lda, b, ldb, beta, c[start*ldc:], ldc)
+	})
+}
+
+func dgemmDispatch(m, n, k int, alpha float64, a []float64, lda int, b []float64, ldb int, beta float64, c []float64, ldc int) {
+	if m*n*k < blockedGemmThreshold {
+		dgemmNaive(m, n, k, alpha, a, lda, b, ldb, beta, c, ldc)
+		return
+	}
+	dgemmBlocked(m, n, k, alpha, a, lda, b, ldb, beta, c, ldc)
+}
+
+// dgemmNaive transposes b into contiguous scratch so that the inner
+// product over k is a stride-1 dot product, then accumulates into c.
+func dgemmNaive(m, n, k int, alpha float64, a []float64, lda int, b []float64, ldb int, beta float64, c []float64, ldc int) {
+	bt := make([]float64, n*k)
+	for p := 0; p < k; p++ {
+		for q := 0; q < n; q++ {
+			bt[q*k+p] = b[p*ldb+q]
+		}
+	}
+	for i := 0; i < m; i++ {
+		arow := a[i*lda : i*lda+k]
+		crow := c[i*ldc : i*ldc+n]
+		for j := 0; j < n; j++ {
+			sum := alpha * dot_vec(arow, bt[j*k:j*k+k])
+			if beta == 0 {
+				crow[j] = sum
+			} else {
+				crow[j] = sum + beta*crow[j]
+			}
+		}
+	}
+}
+
+// dgemmBlocked tiles the (i, j, k) loops into blockSize x blockSize
+// panels. For each (ii, jj) output tile it walks kk, packing a's
+// panel in row-major order and b's panel in column-major order -
+// making the inner kernel a pure stride-1 dot product - and
+// accumulates into a small cTile before writing the tile back through
+// alpha/beta.
+func dgemmBlocked(m, n, k int, alpha float64, a []float64, lda int, b []float64, ldb int, beta float64, c []float64, ldc int) {
+	bs := blockSize
+
+	switch {
+	case beta == 0:
+		// Overwrite rather than scale: c's prior contents are not
+		// part of the contract when beta is 0, and may be uninitialised
+		// scratch (e.g. a pooled workspace buffer), so row[j] *= 0
+		// would turn any Inf/NaN left over in it into NaN.
+		for i := 0; i < m; i++ {
+			row := c[i*ldc : i*ldc+n]
+			for j := range row {
+				row[j] = 0
+			}
+		}
+	case beta != 1:
+		for i := 0; i < m; i++ {
+			row := c[i*ldc : i*ldc+n]
+			for j := range row {
+				row[j] *= beta
+			}
+		}
+	}
+
+	aPanel := make([]float64, bs*bs)
+	bPanel := make([]float64, bs*bs)
+	cTile := make([]float64, bs*bs)
+
+	for ii := 0; ii < m; ii += bs {
+		mi := min(bs, m-ii)
+		for jj := 0; jj < n; jj += bs {
+			nj := min(bs, n-jj)
+			for i := range cTile[:mi*nj] {
+				cTile[i] = 0
+			}
+			for kk := 0; kk < k; kk += bs {
+				kb := min(bs, k-kk)
+
+				for i := 0; i < mi; i++ {
+					copy(aPanel[i*kb:i*kb+kb], a[(ii+i)*lda+kk:(ii+i)*lda+kk+kb])
+				}
+				for p := 0; p < kb; p++ {
+					for q := 0; q < nj; q++ {
+						bPanel[q*kb+p] = b[(kk+p)*ldb+jj+q]
+					}
+				}
+
+				for i := 0; i < mi; i++ {
+					arow := aPanel[i*kb : i*kb+kb]
+					crow := cTile[i*nj : i*nj+nj]
+					for j := 0; j < nj; j++ {
+						crow[j] += dot_vec(arow, bPanel[j*kb:j*kb+kb])
+					}
+				}
+			}
+			for i := 0; i < mi; i++ {
+				crow := c[(ii+i)*ldc+jj : (ii+i)*ldc+jj+nj]
+				tile := cTile[i*nj : i*nj+nj]
+				for j := range crow {
+					crow[j] += alpha * tile[j]
+				}
+			}
+		}
+	}
+}
+
+func (goEngine) Daxpy(n int, alpha float64, x, y []float64) {
+	parallelRows(n, n, func(i int) {
+		y[i] += alpha * x[i]
+	})
+}
+
+func (goEngine) Dscal(n int, alpha float64, x []float64) {
+	parallelRows(n, n, func(i int) {
+		x[i] *= alpha
+	})
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
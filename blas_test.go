@@ -0,0 +1,61 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+import (
+	"math"
+	"testing"
+)
+
+// TestDotBlockedMatchesNaive exercises the blocked Dgemm path (m*n*k
+// above blockedGemmThreshold) and checks it against a brute-force
+// reference, guarding the cache-blocked kernel against packing or
+// tile-boundary bugs that a small, naive-path-only matrix wouldn't
+// exercise.
+func TestDotBlockedMatchesNaive(t *testing.T) {
+	const n = 70 // n^3 > blockedGemmThreshold
+	ma := Randn(n, n)
+	mb := Randn(n, n)
+
+	got := Dot(ma, mb)
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			want := 0.0
+			for k := 0; k < n; k++ {
+				want += ma.At(i, k) * mb.At(k, j)
+			}
+			if diff := got.At(i, j) - want; diff > 1e-9 || diff < -1e-9 {
+				t.Fatalf("At(%d, %d) = %v, want %v", i, j, got.At(i, j), want)
+			}
+		}
+	}
+}
+
+// TestDotBlockedIgnoresPoisonedWorkspace guards dgemmBlocked's beta==0
+// path against a pooled workspace buffer that still holds Inf/NaN from
+// a previous use: beta==0 must overwrite c, not scale it, since
+// 0 * Inf is NaN and the Dgemm contract says c's prior contents are
+// irrelevant when beta is 0.
+func TestDotBlockedIgnoresPoisonedWorkspace(t *testing.T) {
+	const n = 70 // n*n*n > blockedGemmThreshold, so Dot takes the blocked path
+	ws := getWorkspace(n * n)
+	for i := range ws {
+		ws[i] = math.Inf(1)
+	}
+	putWorkspace(ws)
+
+	a := Randn(n, n)
+	b := Randn(n, n)
+	got := a.Dot(a, b) // mat aliases a, so Dot pulls scratch from the pool above
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if v := got.At(i, j); math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Fatalf("At(%d, %d) = %v, want a finite value (stale workspace leaked into result)", i, j, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+import "math"
+
+// NormKind selects which matrix norm Norm computes.
+type NormKind int
+
+const (
+	// MaxAbs is the largest absolute value of any element.
+	MaxAbs NormKind = iota
+	// MaxRowSum is the largest sum of absolute values found in a
+	// single row, maximised over all rows.
+	MaxRowSum
+	// MaxColumnSum is the largest sum of absolute values found in a
+	// single column, maximised over all columns.
+	MaxColumnSum
+	// Frobenius is the square root of the sum of squares of every
+	// element.
+	Frobenius
+)
+
+// Norm computes the norm of m selected by kind.
+func Norm(m Matrix, kind NormKind) float64 {
+	r, c := m.Dims()
+	switch kind {
+	case MaxAbs:
+		max := 0.0
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if v := math.Abs(m.At(i, j)); v > max {
+					max = v
+				}
+			}
+		}
+		return max
+	case MaxRowSum:
+		max := 0.0
+		for i := 0; i < r; i++ {
+			sum := 0.0
+			for j := 0; j < c; j++ {
+				sum += math.Abs(m.At(i, j))
+			}
+			if sum > max {
+				max = sum
+			}
+		}
+		return max
+	case MaxColumnSum:
+		max := 0.0
+		for j := 0; j < c; j++ {
+			sum := 0.0
+			for i := 0; i < r; i++ {
+				sum += math.Abs(m.At(i, j))
+			}
+			if sum > max {
+				max = sum
+			}
+		}
+		return max
+	case Frobenius:
+		sum := 0.0
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				v := m.At(i, j)
+				sum += v * v
+			}
+		}
+		return math.Sqrt(sum)
+	default:
+		panic("gomat: unknown NormKind")
+	}
+}
+
+// LU holds the LU factorization of a square matrix, computed with
+// partial pivoting: P*A = L*U, where L is unit lower triangular and U
+// is upper triangular. LUDecompose computes it; Det and Solve use it.
+type LU struct {
+	lu   *Dense // L (strictly below diagonal) and U (on/above diagonal), packed into one matrix
+	piv  []int  // piv[i] is the original row now occupying row i
+	sign float64
+}
+
+// LUDecompose computes the LU factorization of m with partial pivoting,
+// using Doolittle's method.
+func LUDecompose(m Matrix) *LU {
+	r, c := m.Dims()
+	if r != c {
+		panic("gomat: LU requires a square matrix")
+	}
+	n := r
+	lu := &Dense{rows: n, cols: n, stride: n, data: make([]float64, n*n)}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			lu.data[i*n+j] = m.At(i, j)
+		}
+	}
+	piv := make([]int, n)
+	for i := range piv {
+		piv[i] = i
+	}
+	sign := 1.0
+
+	for k := 0; k < n; k++ {
+		p := k
+		max := math.Abs(lu.data[k*n+k])
+		for i := k + 1; i < n; i++ {
+			if v := math.Abs(lu.data[i*n+k]); v > max {
+				max = v
+				p = i
+			}
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				lu.data[k*n+j], lu.data[p*n+j] = lu.data[p*n+j], lu.data[k*n+j]
+			}
+			piv[k], piv[p] = piv[p], piv[k]
+			sign = -sign
+		}
+		pivot := lu.data[k*n+k]
+		if pivot == 0 {
+			continue // singular; leave remaining multipliers as zero
+		}
+		for i := k + 1; i < n; i++ {
+			mult := lu.data[i*n+k] / pivot
+			lu.data[i*n+k] = mult
+			for j := k + 1; j < n; j++ {
+				lu.data[i*n+j] -= mult * lu.data[k*n+j]
+			}
+		}
+	}
+	return &LU{lu: lu, piv: piv, sign: sign}
+}
+
+// Det returns the determinant of the factorized matrix: the product of
+// U's diagonal, times the sign of the row permutation.
+func (f *LU) Det() float64 {
+	n := f.lu.rows
+	det := f.sign
+	for i := 0; i < n; i++ {
+		det *= f.lu.data[i*n+i]
+	}
+	return det
+}
+
+// Solve returns the solution x of A*x = b, where A is the factorized
+// matrix and b has one column per right-hand side.
+func (f *LU) Solve(b Matrix) *Dense {
+	n := f.lu.rows
+	rb, cb := b.Dims()
+	if rb != n {
+		panic("gomat: incompatible dimensions for LU.Solve")
+	}
+	x := &Dense{rows: n, cols: cb, stride: cb, data: make([]float64, n*cb)}
+	for i := 0; i < n; i++ {
+		for j := 0; j < cb; j++ {
+			x.data[i*cb+j] = b.At(f.piv[i], j)
+		}
+	}
+	// Forward substitution through L, which has an implicit unit diagonal.
+	for i := 0; i < n; i++ {
+		for j := 0; j < cb; j++ {
+			sum := x.data[i*cb+j]
+			for k := 0; k < i; k++ {
+				sum -= f.lu.data[i*f.lu.stride+k] * x.data[k*cb+j]
+			}
+			x.data[i*cb+j] = sum
+		}
+	}
+	// Back substitution through U.
+	for i := n - 1; i >= 0; i-- {
+		for j := 0; j < cb; j++ {
+			sum := x.data[i*cb+j]
+			for k := i + 1; k < n; k++ {
+				sum -= f.lu.data[i*f.lu.stride+k] * x.data[k*cb+j]
+			}
+			x.data[i*cb+j] = sum / f.lu.data[i*f.lu.stride+i]
+		}
+	}
+	return x
+}
+
+// Det returns the determinant of m, computed via its LU factorization.
+func Det(m Matrix) float64 {
+	return LUDecompose(m).Det()
+}
+
+// Inverse returns the inverse of m, computed by solving A*X = I via its
+// LU factorization.
+func Inverse(m Matrix) *Dense {
+	r, c := m.Dims()
+	if r != c {
+		panic("gomat: Inverse requires a square matrix")
+	}
+	id := Zeros(r, r)
+	for i := 0; i < r; i++ {
+		id.Set(i, i, 1)
+	}
+	return LUDecompose(m).Solve(id)
+}
+
+// Solve returns the solution x of a*x = b.
+func Solve(a, b Matrix) *Dense {
+	return LUDecompose(a).Solve(b)
+}
+
+// QR holds the Householder QR factorization of an m x n matrix with
+// m >= n: A = Q*R. R is stored on and above the diagonal of qr; each
+// reflector's v vector (with its leading 1 implicit) is stored below
+// the diagonal of the column it zeroes, and its scaling factor is kept
+// in tau. Q and R materialize the explicit factors on demand.
+type QR struct {
+	qr  *Dense
+	tau []float64
+}
+
+// QRDecompose computes the Householder QR factorization of m, which
+// must have at least as many rows as columns.
+func QRDecompose(m Matrix) *QR {
+	rows, cols := m.Dims()
+	if rows < cols {
+		panic("gomat: QR requires rows >= cols")
+	}
+	qr := &Dense{rows: rows, cols: cols, stride: cols, data: make([]float64, rows*cols)}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			qr.data[i*cols+j] = m.At(i, j)
+		}
+	}
+	tau := make([]float64, cols)
+
+	for k := 0; k < cols; k++ {
+		normX := 0.0
+		for i := k; i < rows; i++ {
+			v := qr.data[i*cols+k]
+			normX += v * v
+		}
+		normX = math.Sqrt(normX)
+		if normX == 0 {
+			tau[k] = 0
+			continue
+		}
+		alpha := qr.data[k*cols+k]
+		sign := 1.0
+		if alpha > 0 {
+			sign = -1.0
+		}
+		vk := alpha - sign*normX
+		v := make([]float64, rows-k)
+		v[0] = 1
+		for i := k + 1; i < rows; i++ {
+			v[i-k] = qr.data[i*cols+k] / vk
+		}
+		tauK := -sign * vk / normX
+		tau[k] = tauK
+
+		// Apply the reflector to the trailing submatrix:
+		// qr[k:,j] -= tau * v * (v^T * qr[k:,j])
+		for j := k; j < cols; j++ {
+			dot := 0.0
+			for i := k; i < rows; i++ {
+				dot += v[i-k] * qr.data[i*cols+j]
+			}
+			dot *= tauK
+			for i := k; i < rows; i++ {
+				qr.data[i*cols+j] -= dot * v[i-k]
+			}
+		}
+		for i := k + 1; i < rows; i++ {
+			qr.data[i*cols+k] = v[i-k]
+		}
+	}
+	return &QR{qr: qr, tau: tau}
+}
+
+// R materializes the upper-triangular factor R.
+func (f *QR) R() *Dense {
+	rows, cols := f.qr.Dims()
+	r := Zeros(rows, cols)
+	for i := 0; i < cols; i++ {
+		for j := i; j < cols; j++ {
+			r.Set(i, j, f.qr.data[i*f.qr.stride+j])
+		}
+	}
+	return r
+}
+
+// Q materializes the orthogonal factor Q by applying the stored
+// reflectors, in reverse order, to the identity matrix.
+func (f *QR) Q() *Dense {
+	rows, cols := f.qr.Dims()
+	q := Zeros(rows, rows)
+	for i := 0; i < rows; i++ {
+		q.Set(i, i, 1)
+	}
+	for k := cols - 1; k >= 0; k-- {
+		tauK := f.tau[k]
+		if tauK == 0 {
+			continue
+		}
+		v := make([]float64, rows-k)
+		v[0] = 1
+		for i := k + 1; i < rows; i++ {
+			v[i-k] = f.qr.data[i*f.qr.stride+k]
+		}
+		for j := 0; j < rows; j++ {
+			dot := 0.0
+			for i := k; i < rows; i++ {
+				dot += v[i-k] * q.data[i*q.stride+j]
+			}
+			dot *= tauK
+			for i := k; i < rows; i++ {
+				q.data[i*q.stride+j] -= dot * v[i-k]
+			}
+		}
+	}
+	return q
+}
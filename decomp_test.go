@@ -0,0 +1,99 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+import (
+	"math"
+	"testing"
+)
+
+const decompTol = 1e-9
+
+func denseApproxEquals(t *testing.T, got Matrix, want [][]float64) {
+	t.Helper()
+	r, c := got.Dims()
+	if r != len(want) || c != len(want[0]) {
+		t.Fatalf("Dims() = (%d, %d), want (%d, %d)", r, c, len(want), len(want[0]))
+	}
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if diff := got.At(i, j) - want[i][j]; math.Abs(diff) > decompTol {
+				t.Fatalf("At(%d, %d) = %v, want %v", i, j, got.At(i, j), want[i][j])
+			}
+		}
+	}
+}
+
+func TestDet2x2(t *testing.T) {
+	a := New([][]float64{{4, 3}, {6, 3}})
+	if diff := Det(a) - (-6); math.Abs(diff) > decompTol {
+		t.Fatalf("Det() = %v, want -6", Det(a))
+	}
+}
+
+func TestInverse2x2(t *testing.T) {
+	a := New([][]float64{{4, 3}, {6, 3}})
+	denseApproxEquals(t, Inverse(a), [][]float64{{-0.5, 0.5}, {1, -2.0 / 3.0}})
+}
+
+func TestSolve2x2(t *testing.T) {
+	a := New([][]float64{{4, 3}, {6, 3}})
+	b := New([][]float64{{1}, {0}})
+	denseApproxEquals(t, Solve(a, b), [][]float64{{-0.5}, {1}})
+}
+
+func TestDetInverseSolve3x3(t *testing.T) {
+	a := New([][]float64{{2, 1, 1}, {1, 3, 2}, {1, 0, 0}})
+
+	if diff := Det(a) - (-1); math.Abs(diff) > decompTol {
+		t.Fatalf("Det() = %v, want -1", Det(a))
+	}
+	denseApproxEquals(t, Inverse(a), [][]float64{
+		{0, 0, 1},
+		{-2, 1, 3},
+		{3, -1, -5},
+	})
+	b := New([][]float64{{1}, {2}, {3}})
+	denseApproxEquals(t, Solve(a, b), [][]float64{{3}, {9}, {-14}})
+}
+
+// TestDetSingular checks that a singular matrix (row 2 is a multiple
+// of row 1) is recognised via a zero determinant rather than e.g.
+// dividing by a zero pivot and blowing up.
+func TestDetSingular(t *testing.T) {
+	a := New([][]float64{{1, 2, 3}, {2, 4, 6}, {1, 1, 1}})
+	if diff := Det(a); math.Abs(diff) > decompTol {
+		t.Fatalf("Det() = %v, want 0", diff)
+	}
+}
+
+// TestQRDecompose checks that Q and R reconstruct the input matrix and
+// that Q is orthogonal, i.e. Q^T * Q = I.
+func TestQRDecompose(t *testing.T) {
+	a := New([][]float64{{2, 1, 1}, {1, 3, 2}, {1, 0, 5}})
+
+	f := QRDecompose(a)
+	q, r := f.Q(), f.R()
+
+	denseApproxEquals(t, Dot(q, r), [][]float64{
+		{2, 1, 1},
+		{1, 3, 2},
+		{1, 0, 5},
+	})
+
+	rows, _ := q.Dims()
+	qtq := Dot(q.T(), q)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < rows; j++ {
+			want := 0.0
+			if i == j {
+				want = 1.0
+			}
+			if diff := qtq.At(i, j) - want; math.Abs(diff) > decompTol {
+				t.Fatalf("(Q^T*Q)[%d][%d] = %v, want %v (Q not orthogonal)", i, j, qtq.At(i, j), want)
+			}
+		}
+	}
+}
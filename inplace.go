@@ -0,0 +1,352 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// This file adds receiver-style, in-place counterparts to the
+// package-level matrix operations (Add, Sub, Mul, Scale, Dot, Sigmoid,
+// Transpose). They write their result into the receiver, growing or
+// reusing its backing slice via reuseAs instead of allocating a fresh
+// Dense on every call - the allocation-per-call cost of the
+// package-level functions is prohibitive in training loops that call
+// Dot/Add thousands of times.
+
+// workspacePool holds scratch []float64 buffers used by in-place
+// operations that must compute into scratch before copying back into
+// an aliased receiver (see getWorkspace/putWorkspace).
+var workspacePool = sync.Pool{
+	New: func() interface{} { return new([]float64) },
+}
+
+// getWorkspace returns a scratch slice of length n, reusing a pooled
+// buffer when one large enough is available.
+func getWorkspace(n int) []float64 {
+	buf := workspacePool.Get().(*[]float64)
+	if cap(*buf) < n {
+		*buf = make([]float64, n)
+	}
+	return (*buf)[:n]
+}
+
+// putWorkspace returns a scratch slice obtained from getWorkspace to
+// the pool for reuse.
+func putWorkspace(s []float64) {
+	workspacePool.Put(&s)
+}
+
+// reuseAs reconfigures mat to be an r x c contiguous Dense, reusing its
+// existing backing slice when it already has enough capacity instead
+// of allocating a new one. A mat whose stride doesn't already equal
+// its cols is a strided Slice view into a larger parent Dense - its
+// backing slice has gaps between rows, so reslicing it as if it were
+// r*c densely packed elements would read/write into the parent's
+// unrelated rows. Such a mat always gets fresh storage instead, unless
+// selfAliased is set, meaning mat is itself one of the operands the
+// operation still needs to read: r and c already equal mat's own dims
+// in that case, so there is nothing to resize, and reallocating would
+// discard the data the operand is about to be read from.
+func (mat *Dense) reuseAs(r, c int, selfAliased bool) {
+	if selfAliased {
+		return
+	}
+	if mat.stride != mat.cols || cap(mat.data) < r*c {
+		mat.rows, mat.cols, mat.stride = r, c, c
+		mat.data = make([]float64, r*c)
+		return
+	}
+	mat.rows, mat.cols, mat.stride = r, c, c
+	mat.data = mat.data[:r*c]
+}
+
+// sameData reports whether a and b are the same slice, i.e. mat and an
+// operand are literally the same matrix - the case the receiver-style
+// API is built around (e.g. m.Add(m, b)).
+func sameData(a, b []float64) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == 0 && len(b) == 0
+	}
+	return &a[0] == &b[0] && len(a) == len(b)
+}
+
+// overlaps reports whether a and b share any backing array elements.
+func overlaps(a, b []float64) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	var zero float64
+	sz := unsafe.Sizeof(zero)
+	lo, hi := uintptr(unsafe.Pointer(&a[0])), uintptr(unsafe.Pointer(&a[len(a)-1]))+sz
+	blo, bhi := uintptr(unsafe.Pointer(&b[0])), uintptr(unsafe.Pointer(&b[len(b)-1]))+sz
+	return lo < bhi && blo < hi
+}
+
+// overlapsMatrix reports whether mat's backing slice shares any
+// elements with x's, for x backed by a *Dense, either directly or
+// through Transpose wrappers.
+func (mat *Dense) overlapsMatrix(x Matrix) bool {
+	for {
+		t, ok := x.(Transpose)
+		if !ok {
+			break
+		}
+		x = t.Matrix
+	}
+	d, ok := x.(*Dense)
+	if !ok {
+		return false
+	}
+	return overlaps(mat.data, d.data)
+}
+
+// checkOverlap panics if mat's backing slice partially overlaps one of
+// mats in a way that would corrupt the result. Exact aliasing (mat is
+// literally one of the operands) is fine; any other overlap - e.g. mat
+// being a Slice view into the same backing array as an operand - is
+// not supported because element order during the write would corrupt
+// reads still pending on the operand.
+func (mat *Dense) checkOverlap(mats ...Matrix) {
+	for _, x := range mats {
+		if _, ok := x.(Transpose); ok {
+			// A transposed view is never an exact alias: element (i, j)
+			// would be read from where (j, i) is being written.
+			if mat.overlapsMatrix(x) {
+				panic("gomat: receiver overlaps operand in an unsupported way")
+			}
+			continue
+		}
+		d, ok := x.(*Dense)
+		if !ok || sameData(mat.data, d.data) {
+			continue
+		}
+		if overlaps(mat.data, d.data) {
+			panic("gomat: receiver overlaps operand in an unsupported way")
+		}
+	}
+}
+
+// aliasesData reports whether mat's backing slice is exactly one of
+// mats' - the safe, self-aliasing case checkOverlap already lets
+// through (e.g. m.Add(m, b)), as opposed to merely overlapping it.
+func (mat *Dense) aliasesData(mats ...Matrix) bool {
+	for _, x := range mats {
+		if d, ok := x.(*Dense); ok && sameData(mat.data, d.data) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add computes a+b element-wise into mat, and returns mat.
+func (mat *Dense) Add(a, b Matrix) *Dense {
+	ra, ca := a.Dims()
+	rb, cb := b.Dims()
+	if ra != rb || ca != cb {
+		panic("Dimensions of matrix A and matrix B must be equal")
+	}
+	mat.checkOverlap(a, b)
+	mat.reuseAs(ra, ca, mat.aliasesData(a, b))
+	if da, ok := asContiguous(a); ok {
+		if db, ok := asContiguous(b); ok {
+			switch {
+			case sameData(mat.data, db.data):
+				// mat already holds b; accumulate a into it in place
+				// rather than copying a over it, which would clobber
+				// the b Daxpy still needs to read.
+				engine.Daxpy(len(mat.data), 1.0, da.data, mat.data)
+			case sameData(mat.data, da.data):
+				engine.Daxpy(len(mat.data), 1.0, db.data, mat.data)
+			default:
+				copy(mat.data, da.data)
+				engine.Daxpy(len(mat.data), 1.0, db.data, mat.data)
+			}
+			return mat
+		}
+	}
+	parallelRows(ra, ra*ca, func(i int) {
+		for j := 0; j < ca; j++ {
+			mat.Set(i, j, a.At(i, j)+b.At(i, j))
+		}
+	})
+	return mat
+}
+
+// Sub computes a-b element-wise into mat, and returns mat.
+func (mat *Dense) Sub(a, b Matrix) *Dense {
+	ra, ca := a.Dims()
+	rb, cb := b.Dims()
+	if ra != rb || ca != cb {
+		panic("Dimensions of matrix A and matrix B must be equal")
+	}
+	mat.checkOverlap(a, b)
+	mat.reuseAs(ra, ca, mat.aliasesData(a, b))
+	if da, ok := asContiguous(a); ok {
+		if db, ok := asContiguous(b); ok {
+			switch {
+			case sameData(mat.data, db.data):
+				// mat already holds b; Daxpy can only accumulate, so
+				// negate it in place first and then add a, instead of
+				// copying a over it and clobbering the b we still need.
+				engine.Dscal(len(mat.data), -1.0, mat.data)
+				engine.Daxpy(len(mat.data), 1.0, da.data, mat.data)
+			case sameData(mat.data, da.data):
+				engine.Daxpy(len(mat.data), -1.0, db.data, mat.data)
+			default:
+				copy(mat.data, da.data)
+				engine.Daxpy(len(mat.data), -1.0, db.data, mat.data)
+			}
+			return mat
+		}
+	}
+	parallelRows(ra, ra*ca, func(i int) {
+		for j := 0; j < ca; j++ {
+			mat.Set(i, j, a.At(i, j)-b.At(i, j))
+		}
+	})
+	return mat
+}
+
+// Mul applies the Hadamard product of a and b element-wise into mat,
+// and returns mat.
+func (mat *Dense) Mul(a, b Matrix) *Dense {
+	ra, ca := a.Dims()
+	rb, cb := b.Dims()
+	if ra != rb || ca != cb {
+		panic("Dimensions of matrix A and matrix B must be equal")
+	}
+	mat.checkOverlap(a, b)
+	mat.reuseAs(ra, ca, mat.aliasesData(a, b))
+	if da, ok := asContiguous(a); ok {
+		if db, ok := asContiguous(b); ok {
+			parallelRows(ra, ra*ca, func(i int) {
+				row := mat.data[i*ca : i*ca+ca]
+				arow := da.data[i*ca : i*ca+ca]
+				brow := db.data[i*ca : i*ca+ca]
+				for j := range row {
+					row[j] = arow[j] * brow[j]
+				}
+			})
+			return mat
+		}
+	}
+	parallelRows(ra, ra*ca, func(i int) {
+		for j := 0; j < ca; j++ {
+			mat.Set(i, j, a.At(i, j)*b.At(i, j))
+		}
+	})
+	return mat
+}
+
+// Scale multiplies a by the scalar v element-wise into mat, and
+// returns mat.
+func (mat *Dense) Scale(v float64, a Matrix) *Dense {
+	r, c := a.Dims()
+	mat.checkOverlap(a)
+	mat.reuseAs(r, c, mat.aliasesData(a))
+	if da, ok := asContiguous(a); ok {
+		if !sameData(mat.data, da.data) {
+			copy(mat.data, da.data)
+		}
+		engine.Dscal(len(mat.data), v, mat.data)
+		return mat
+	}
+	parallelRows(r, r*c, func(i int) {
+		for j := 0; j < c; j++ {
+			mat.Set(i, j, v*a.At(i, j))
+		}
+	})
+	return mat
+}
+
+// Sigmoid applies the sigmoid function element-wise from a into mat,
+// and returns mat.
+func (mat *Dense) Sigmoid(a Matrix) *Dense {
+	r, c := a.Dims()
+	mat.checkOverlap(a)
+	mat.reuseAs(r, c, mat.aliasesData(a))
+	if da, ok := asContiguous(a); ok {
+		parallelRows(r, r*c, func(i int) {
+			row := mat.data[i*c : i*c+c]
+			arow := da.data[i*c : i*c+c]
+			for j, val := range arow {
+				row[j] = sigmoid(val)
+			}
+		})
+		return mat
+	}
+	parallelRows(r, r*c, func(i int) {
+		for j := 0; j < c; j++ {
+			mat.Set(i, j, sigmoid(a.At(i, j)))
+		}
+	})
+	return mat
+}
+
+// Dot computes a·b into mat, using the active BlasEngine where
+// possible, and returns mat. If mat aliases either operand, the
+// product is computed into a pooled scratch workspace first and
+// copied into mat afterwards, since writing into mat while rows or
+// columns of a or b are still being read would corrupt the result.
+func (mat *Dense) Dot(a, b Matrix) *Dense {
+	ra, ca := a.Dims()
+	rb, cb := b.Dims()
+	if ca != rb {
+		panic("Num cols in matrix A must be equal to num rows in matrix B")
+	}
+	if mat.overlapsMatrix(a) || mat.overlapsMatrix(b) {
+		ws := getWorkspace(ra * cb)
+		defer putWorkspace(ws)
+		dst := &Dense{rows: ra, cols: cb, stride: cb, data: ws}
+		dotInto(dst, a, b)
+		mat.reuseAs(ra, cb, false)
+		copy(mat.data, dst.data)
+		return mat
+	}
+	mat.reuseAs(ra, cb, false)
+	dotInto(mat, a, b)
+	return mat
+}
+
+// Transpose materializes the transpose of a into mat, and returns mat.
+// Unlike a's T() method, this copies data rather than returning an
+// implicit view, which is useful when the result needs to be walked
+// with stride-1 access afterwards.
+func (mat *Dense) Transpose(a Matrix) *Dense {
+	r, c := a.Dims()
+	if mat.overlapsMatrix(a) {
+		ws := getWorkspace(c * r)
+		defer putWorkspace(ws)
+		dst := &Dense{rows: c, cols: r, stride: r, data: ws}
+		transposeInto(dst, a)
+		mat.reuseAs(c, r, false)
+		copy(mat.data, dst.data)
+		return mat
+	}
+	mat.reuseAs(c, r, false)
+	transposeInto(mat, a)
+	return mat
+}
+
+// transposeInto writes the transpose of a into dst, which must already
+// be sized to a's column x row dimensions.
+func transposeInto(dst *Dense, a Matrix) {
+	r, c := a.Dims()
+	if da, ok := asContiguous(a); ok {
+		for j := 0; j < c; j++ {
+			for i := 0; i < r; i++ {
+				dst.data[j*dst.stride+i] = da.data[i*da.stride+j]
+			}
+		}
+		return
+	}
+	for j := 0; j < c; j++ {
+		for i := 0; i < r; i++ {
+			dst.data[j*dst.stride+i] = a.At(i, j)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+import "testing"
+
+func denseEquals(t *testing.T, got *Dense, want [][]float64) {
+	t.Helper()
+	r, c := got.Dims()
+	if r != len(want) || c != len(want[0]) {
+		t.Fatalf("Dims() = (%d, %d), want (%d, %d)", r, c, len(want), len(want[0]))
+	}
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if got.At(i, j) != want[i][j] {
+				t.Fatalf("At(%d, %d) = %v, want %v", i, j, got.At(i, j), want[i][j])
+			}
+		}
+	}
+}
+
+func TestAddAliasA(t *testing.T) {
+	a := New([][]float64{{1, 2}, {3, 4}})
+	b := New([][]float64{{5, 6}, {7, 8}})
+	a.Add(a, b)
+	denseEquals(t, a, [][]float64{{6, 8}, {10, 12}})
+}
+
+func TestAddAliasB(t *testing.T) {
+	a := New([][]float64{{1, 2}, {3, 4}})
+	b := New([][]float64{{5, 6}, {7, 8}})
+	b.Add(a, b)
+	denseEquals(t, b, [][]float64{{6, 8}, {10, 12}})
+}
+
+func TestSubAliasA(t *testing.T) {
+	a := New([][]float64{{5, 6}, {7, 8}})
+	b := New([][]float64{{1, 2}, {3, 4}})
+	a.Sub(a, b)
+	denseEquals(t, a, [][]float64{{4, 4}, {4, 4}})
+}
+
+func TestSubAliasB(t *testing.T) {
+	a := New([][]float64{{5, 6}, {7, 8}})
+	b := New([][]float64{{1, 2}, {3, 4}})
+	b.Sub(a, b)
+	denseEquals(t, b, [][]float64{{4, 4}, {4, 4}})
+}
+
+// TestInPlaceSliceView guards against reuseAs reinterpreting a strided
+// Slice view's backing window as densely packed. Since a Slice view's
+// data has gaps between rows, an in-place op on it must allocate fresh
+// storage for its result rather than writing through the view's window
+// into the parent Dense, which would corrupt the parent's unrelated
+// rows and columns.
+func TestInPlaceSliceView(t *testing.T) {
+	parent := Zeros(4, 4)
+	view := parent.Slice(1, 3, 1, 3)
+	a := New([][]float64{{1, 2}, {3, 4}})
+	b := New([][]float64{{5, 6}, {7, 8}})
+
+	view.Add(a, b)
+
+	denseEquals(t, view, [][]float64{{6, 8}, {10, 12}})
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if parent.At(i, j) != 0 {
+				t.Fatalf("parent.At(%d, %d) = %v, want 0 (Slice view write corrupted the parent)",
+					i, j, parent.At(i, j))
+			}
+		}
+	}
+}
+
+// TestInPlaceSliceViewSelfAlias guards the opposite failure mode: when
+// a Slice view is its own operand (view.Add(view, b), the common
+// receiver-style call pattern), reuseAs must not discard the view's
+// existing data before it is read.
+func TestInPlaceSliceViewSelfAlias(t *testing.T) {
+	parent := New([][]float64{
+		{0, 0, 0, 0},
+		{0, 1, 2, 0},
+		{0, 3, 4, 0},
+		{0, 0, 0, 0},
+	})
+	view := parent.Slice(1, 3, 1, 3)
+	b := New([][]float64{{5, 6}, {7, 8}})
+
+	view.Add(view, b)
+
+	denseEquals(t, view, [][]float64{{6, 8}, {10, 12}})
+	denseEquals(t, parent.Slice(1, 3, 1, 3), [][]float64{{6, 8}, {10, 12}})
+}
@@ -3,8 +3,8 @@
 // that can be found in the LICENSE file.
 
 // Package gomat is a simple matrix implemenation.
-// At the core is the Matrix struct, which simply
-// wraps a slice of floats, i.e. []float64.
+// At the core is the Matrix interface, implemented by Dense,
+// which wraps a slice of floats, i.e. []float64.
 // We make a light attempt at optimising cache locality;
 // however it is by no means the most optimal implementation.
 //
@@ -26,30 +26,117 @@ import (
 	"math/rand"
 )
 
-type Matrix struct {
-	m int
-	n int
-	data []float64
+// Matrix is implemented by any type that can report its dimensions,
+// read back an element, and produce its transpose. Dense is the only
+// implementation provided by gomat that owns storage; Transpose wraps a
+// Matrix to present an implicit transpose of it.
+type Matrix interface {
+	// Dims returns the number of rows and columns held by the Matrix.
+	Dims() (r, c int)
+	// At returns the element at row i, column j.
+	At(i, j int) float64
+	// T returns the transpose of the Matrix. Where possible this is an
+	// implicit view rather than a copy.
+	T() Matrix
 }
 
-// Private Matrix methods
+// Dense is a dense matrix backed by a single []float64. Rows of a Dense
+// are 'stride' elements apart, which need not equal 'cols' - this lets
+// Slice hand out zero-copy submatrix views that share the same backing
+// array as the Matrix they were sliced from.
+type Dense struct {
+	rows, cols, stride int
+	data                []float64
+}
+
+// Private Dense methods
+
+func (mat *Dense) index_at(i, j int) int {
+	return i*mat.stride + j
+}
+
+// Public Dense methods
+
+// Dims returns the number of rows and columns in mat.
+func (mat *Dense) Dims() (r, c int) {
+	return mat.rows, mat.cols
+}
+
+// At returns the element at row i, column j.
+func (mat *Dense) At(i, j int) float64 {
+	return mat.data[mat.index_at(i, j)]
+}
+
+// Set assigns v to the element at row i, column j.
+func (mat *Dense) Set(i, j int, v float64) {
+	mat.data[mat.index_at(i, j)] = v
+}
+
+// ValueAt returns the element at row i, column j. It is equivalent to
+// At.
+func (mat *Dense) ValueAt(i, j int) float64 {
+	return mat.At(i, j)
+}
+
+// ValueAtIndex returns the element at the given zero-based offset into
+// mat, as if its rows were laid out contiguously in row-major order.
+func (mat *Dense) ValueAtIndex(index int) float64 {
+	return mat.At(index/mat.cols, index%mat.cols)
+}
+
+// T returns an implicit, zero-copy transpose of mat.
+func (mat *Dense) T() Matrix {
+	return Transpose{Matrix: mat}
+}
+
+// Cols returns the number of columns in mat.
+func (mat *Dense) Cols() int {
+	return mat.cols
+}
+
+// Rows returns the number of rows in mat.
+func (mat *Dense) Rows() int {
+	return mat.rows
+}
 
-func (mat *Matrix) index_at(i, j int) int {
-	return i * mat.n + j
+// Slice returns a Matrix view over the submatrix spanning rows [i, k)
+// and columns [j, l) of mat. The view shares mat's backing array, so
+// writes through Set on either are visible in the other; no data is
+// copied. The in-place operations in this package are an exception:
+// calling one of them on a Slice view (other than as its own operand,
+// e.g. view.Add(view, b)) always allocates the view a fresh backing
+// array for its result rather than writing through into mat, since mat
+// may have rows or columns outside the view that must not be touched.
+func (mat *Dense) Slice(i, k, j, l int) *Dense {
+	if i < 0 || j < 0 || k > mat.rows || l > mat.cols || i >= k || j >= l {
+		panic("gomat: slice indices out of range")
+	}
+	off := mat.index_at(i, j)
+	end := off + (k-i-1)*mat.stride + (l - j)
+	return &Dense{rows: k - i, cols: l - j, stride: mat.stride, data: mat.data[off:end]}
 }
 
-func (mat *Matrix) value_at(i, j int) float64 {
-	return mat.data[i * mat.n + j]
+// Transpose is a Matrix that presents an implicit transpose of another
+// Matrix, without copying its data.
+type Transpose struct {
+	Matrix Matrix
 }
 
-// Public Matrix methods
+// Dims returns the number of rows and columns in the transpose, i.e.
+// the number of columns and rows of the wrapped Matrix.
+func (t Transpose) Dims() (r, c int) {
+	c, r = t.Matrix.Dims()
+	return r, c
+}
 
-func (mat *Matrix) Cols() int {
-	return mat.n
+// At returns the element at row i, column j of the transpose.
+func (t Transpose) At(i, j int) float64 {
+	return t.Matrix.At(j, i)
 }
 
-func (mat *Matrix) Rows() int {
-	return mat.m
+// T undoes the transpose, returning the wrapped Matrix.
+func (t Transpose) T() Matrix {
+	return t.Matrix
 }
 
 // Private helper functions used by matrix operations
@@ -82,72 +169,144 @@ func sub_vec(va, vb, vc []float64) {
 	}
 }
 
+// asContiguous reports whether m is a *Dense with no gaps between rows,
+// i.e. not a strided view, so its data can be handed to the BlasEngine
+// or walked with a flat loop.
+func asContiguous(m Matrix) (*Dense, bool) {
+	d, ok := m.(*Dense)
+	if !ok || d.stride != d.cols {
+		return nil, false
+	}
+	return d, true
+}
+
 // Public functions that implement matrix operations
 
 // Add performs addition of two matrices 'ma' and 'mb' and
-// returns a ptr to the resulting matrix
-func Add(ma, mb *Matrix) *Matrix {
-	if ma.m != mb.m && ma.n != mb.n {
+// returns a ptr to the resulting matrix. Where both operands are
+// contiguous, the addition is routed through the active BlasEngine's
+// Daxpy kernel; otherwise it falls back to a generic loop over At.
+func Add(ma, mb Matrix) *Dense {
+	ra, ca := ma.Dims()
+	rb, cb := mb.Dims()
+	if ra != rb || ca != cb {
 		panic("Dimensions of matrix A and matrix B must be equal")
 	}
-	mc := &Matrix{ma.m, ma.n, make([]float64, ma.m * ma.n)}
-	if ma.n > 15 {
-		r, c := ma.m, ma.n
-		for i := 0; i < r; i++ {
-			add_vec(ma.data[i*c:i*c+c],
-				mb.data[i*c:i*c+c], mc.data[i*c:i*c+c])
-		}
-	} else {
-		for i := 0; i < ma.m * ma.n; i++ {
-			mc.data[i] = ma.data[i] + mb.data[i]
+	mc := &Dense{ra, ca, ca, make([]float64, ra*ca)}
+	if da, ok := asContiguous(ma); ok {
+		if db, ok := asContiguous(mb); ok {
+			copy(mc.data, da.data)
+			engine.Daxpy(len(mc.data), 1.0, db.data, mc.data)
+			return mc
 		}
 	}
+	parallelRows(ra, ra*ca, func(i int) {
+		for j := 0; j < ca; j++ {
+			mc.Set(i, j, ma.At(i, j)+mb.At(i, j))
+		}
+	})
 	return mc
 }
 
 // Dot performs dot product of matrices 'ma' and 'mb' and
 // returns a ptr to the resulting matrix. Each row in 'ma' is
-// multiplied by column in 'mb'
-func Dot(ma, mb *Matrix) *Matrix {
-	if ma.n != mb.m {
+// multiplied by column in 'mb'. When both operands are contiguous the
+// multiplication is routed through the active BlasEngine's Dgemm
+// kernel, mirroring DGEMM; when 'mb' is an implicit Transpose of a
+// contiguous Dense, its rows are used directly as dot-product operands
+// so no transposed copy is ever materialised.
+func Dot(ma, mb Matrix) *Dense {
+	ra, ca := ma.Dims()
+	rb, cb := mb.Dims()
+	if ca != rb {
 		panic("Num cols in matrix A must be equal to num rows in matrix B")
 	}
-	mc := &Matrix{ma.m, mb.n, make([]float64, ma.m * mb.n)}
-	tr_mb := Transpose(mb)
-	for i := 0; i < mc.m; i++ {
-		for j := 0; j < tr_mb.m; j++ {
-			mc.data[mc.index_at(i, j)] = dot_vec(ma.data[i*ma.n:i*ma.n+ma.n],
-				tr_mb.data[j*tr_mb.n:j*tr_mb.n+tr_mb.n])
+	mc := &Dense{ra, cb, cb, make([]float64, ra*cb)}
+	dotInto(mc, ma, mb)
+	return mc
+}
+
+// dotInto writes ma·mb into dst, which must already be sized to
+// ma.rows x mb.cols. When both operands are contiguous the
+// multiplication is routed through the active BlasEngine's Dgemm
+// kernel, mirroring DGEMM; when mb is an implicit Transpose of a
+// contiguous Dense, its rows are used directly as dot-product operands
+// so no transposed copy is ever materialised.
+func dotInto(dst *Dense, ma, mb Matrix) {
+	ra, ca := ma.Dims()
+	_, cb := mb.Dims()
+
+	if da, ok := asContiguous(ma); ok {
+		if db, ok := asContiguous(mb); ok {
+			engine.Dgemm(ra, cb, ca, 1.0, da.data, da.stride, db.data, db.stride, 0.0, dst.data, dst.stride)
+			return
+		}
+		if t, ok := mb.(Transpose); ok {
+			if db, ok := asContiguous(t.Matrix); ok {
+				parallelRows(ra, ra*cb*ca, func(i int) {
+					arow := da.data[i*da.stride : i*da.stride+ca]
+					for j := 0; j < cb; j++ {
+						dst.data[i*dst.stride+j] = dot_vec(arow, db.data[j*db.stride:j*db.stride+db.cols])
+					}
+				})
+				return
+			}
 		}
 	}
-	return mc
+
+	parallelRows(ra, ra*cb*ca, func(i int) {
+		for j := 0; j < cb; j++ {
+			sum := 0.0
+			for k := 0; k < ca; k++ {
+				sum += ma.At(i, k) * mb.At(k, j)
+			}
+			dst.data[i*dst.stride+j] = sum
+		}
+	})
 }
 
 // FromVec takes a slice of M elements and
 // returns a ptr to a Mx1 matrix
-func FromVec(vec []float64) *Matrix {
+func FromVec(vec []float64) *Dense {
 	if len(vec) == 0 {
 		panic("Empty input vector")
 	}
-	return &Matrix{len(vec), 1, vec}
+	return &Dense{rows: len(vec), cols: 1, stride: 1, data: vec}
 }
 
 // Mul applies Hadamard product between matrix 'ma' and 'mb'
 // and returns a ptr to the resulting matrix
-func Mul(ma, mb *Matrix) *Matrix {
-	if ma.m != mb.m && ma.n != mb.n {
+func Mul(ma, mb Matrix) *Dense {
+	ra, ca := ma.Dims()
+	rb, cb := mb.Dims()
+	if ra != rb || ca != cb {
 		panic("Dimensions of matrix A and matrix B must be equal")
 	}
-	mc := &Matrix{ma.m, ma.n, make([]float64, ma.m * ma.n)}
-	for i := 0; i < ma.m * ma.n; i++ {
-		mc.data[i] = ma.data[i] * mb.data[i]
+	mc := &Dense{ra, ca, ca, make([]float64, ra*ca)}
+	if da, ok := asContiguous(ma); ok {
+		if db, ok := asContiguous(mb); ok {
+			parallelRows(ra, ra*ca, func(i int) {
+				row := mc.data[i*ca : i*ca+ca]
+				arow := da.data[i*ca : i*ca+ca]
+				brow := db.data[i*ca : i*ca+ca]
+				for j := range row {
+					row[j] = arow[j] * brow[j]
+				}
+			})
+			return mc
+		}
 	}
+	parallelRows(ra, ra*ca, func(i int) {
+		for j := 0; j < ca; j++ {
+			mc.Set(i, j, ma.At(i, j)*mb.At(i, j))
+		}
+	})
 	return mc
 }
 
 // New returns a ptr to a MxN matrix using manually-inputted floats
 // specified by 'd'
-func New(d [][]float64) *Matrix {
+func New(d [][]float64) *Dense {
 	m := len(d)
 	if m == 0 {
 		panic("No rows defined")
@@ -156,7 +315,7 @@ func New(d [][]float64) *Matrix {
 	if n == 0 {
 		panic("No columns defined")
 	}
-	mat := &Matrix{m, n, make([]float64, m*n)}
+	mat := &Dense{rows: m, cols: n, stride: n, data: make([]float64, m*n)}
 	for i := 0; i < m; i++ {
 		if len(d[i]) != n {
 			panic("All rows must be equal length")
@@ -168,11 +327,20 @@ func New(d [][]float64) *Matrix {
 	return mat
 }
 
+// Ones returns a ptr to a m x n matrix with all elements set to 1
+func Ones(m, n int) *Dense {
+	mat := &Dense{rows: m, cols: n, stride: n, data: make([]float64, m*n)}
+	for i := range mat.data {
+		mat.data[i] = 1
+	}
+	return mat
+}
+
 // Randn returns a ptr to a m x n matrix with random
 // normally distributed values, i.e. mean=0, stdev=1
-func Randn(m, n int) *Matrix {
-	mat := &Matrix{m, n, make([]float64, m * n)}
-	for i := 0; i < m * n; i++ {
+func Randn(m, n int) *Dense {
+	mat := &Dense{rows: m, cols: n, stride: n, data: make([]float64, m*n)}
+	for i := 0; i < m*n; i++ {
 		mat.data[i] = rand.NormFloat64()
 	}
 	return mat
@@ -180,68 +348,92 @@ func Randn(m, n int) *Matrix {
 
 // Sigmoid applies the sigmoid function element-wise
 // on matrix 'ma' and returns a ptr to the resulting matrix
-func Sigmoid(ma *Matrix) *Matrix {
-	mb := &Matrix{ma.m, ma.n, make([]float64, ma.m * ma.n)}
-	for i, val := range ma.data {
-		mb.data[i] = sigmoid(val)
+func Sigmoid(ma Matrix) *Dense {
+	r, c := ma.Dims()
+	mb := &Dense{rows: r, cols: c, stride: c, data: make([]float64, r*c)}
+	if da, ok := asContiguous(ma); ok {
+		parallelRows(r, r*c, func(i int) {
+			row := mb.data[i*c : i*c+c]
+			arow := da.data[i*c : i*c+c]
+			for j, val := range arow {
+				row[j] = sigmoid(val)
+			}
+		})
+		return mb
 	}
+	parallelRows(r, r*c, func(i int) {
+		for j := 0; j < c; j++ {
+			mb.Set(i, j, sigmoid(ma.At(i, j)))
+		}
+	})
 	return mb
 }
 
 // Sigmoidpr applies the sigmoid derivative function element-wise
 // on matrix 'ma' and returns a ptr to the resulting matrix
-func Sigmoidpr(ma *Matrix) *Matrix {
-	mb := &Matrix{ma.m, ma.n, make([]float64, ma.m * ma.n)}
-	for i, val := range ma.data {
-		mb.data[i] = sigmoid_prime(val)
+func Sigmoidpr(ma Matrix) *Dense {
+	r, c := ma.Dims()
+	mb := &Dense{rows: r, cols: c, stride: c, data: make([]float64, r*c)}
+	if da, ok := asContiguous(ma); ok {
+		for i, val := range da.data {
+			mb.data[i] = sigmoid_prime(val)
+		}
+		return mb
+	}
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			mb.Set(i, j, sigmoid_prime(ma.At(i, j)))
+		}
 	}
 	return mb
 }
 
 // Scale multiplies scalar 'v' by matrix 'ma' and returns
-// a ptr to the resulting matrix
-func Scale(v float64, ma *Matrix) *Matrix {
-	mb := &Matrix{ma.m, ma.n, make([]float64, ma.m * ma.n)}
-	for i, val := range ma.data {
-		mb.data[i] = val * v
+// a ptr to the resulting matrix. Where 'ma' is contiguous, the scaling
+// is routed through the active BlasEngine's Dscal kernel.
+func Scale(v float64, ma Matrix) *Dense {
+	r, c := ma.Dims()
+	mb := &Dense{rows: r, cols: c, stride: c, data: make([]float64, r*c)}
+	if da, ok := asContiguous(ma); ok {
+		copy(mb.data, da.data)
+		engine.Dscal(len(mb.data), v, mb.data)
+		return mb
 	}
+	parallelRows(r, r*c, func(i int) {
+		for j := 0; j < c; j++ {
+			mb.Set(i, j, v*ma.At(i, j))
+		}
+	})
 	return mb
 }
 
 // Sub subtracts 'mb' from matrix 'ma' and returns
-// a ptr to the resulting matrix
-func Sub(ma, mb *Matrix) *Matrix {
-	if ma.m != mb.m && ma.n != mb.n {
+// a ptr to the resulting matrix. Where both operands are contiguous,
+// the subtraction is routed through the active BlasEngine's Daxpy
+// kernel.
+func Sub(ma, mb Matrix) *Dense {
+	ra, ca := ma.Dims()
+	rb, cb := mb.Dims()
+	if ra != rb || ca != cb {
 		panic("Dimensions of matrix A and matrix B must be equal")
 	}
-	mc := &Matrix{ma.m, ma.n, make([]float64, ma.m * ma.n)}
-	if ma.n > 15 {
-		r, c := ma.m, ma.n
-		for i := 0; i < r; i++ {
-			sub_vec(ma.data[i*c:i*c+c],
-				mb.data[i*c:i*c+c], mc.data[i*c:i*c+c])
-		}
-	} else {
-		for i := 0; i < ma.m * ma.n; i++ {
-			mc.data[i] = ma.data[i] - mb.data[i]
+	mc := &Dense{ra, ca, ca, make([]float64, ra*ca)}
+	if da, ok := asContiguous(ma); ok {
+		if db, ok := asContiguous(mb); ok {
+			copy(mc.data, da.data)
+			engine.Daxpy(len(mc.data), -1.0, db.data, mc.data)
+			return mc
 		}
 	}
-	return mc
-}
-
-// Transpose performs a transpose on matrix 'ma' and returns
-// a ptr to the resulting matrix
-func Transpose(ma *Matrix) *Matrix {
-	mb := &Matrix{ma.n, ma.m, make([]float64, ma.m * ma.n)}
-	for j := 0; j < ma.n; j++ {
-		for i := 0; i < ma.m; i++ {
-			mb.data[j * mb.n + i] = ma.value_at(i, j)
+	parallelRows(ra, ra*ca, func(i int) {
+		for j := 0; j < ca; j++ {
+			mc.Set(i, j, ma.At(i, j)-mb.At(i, j))
 		}
-	}
-	return mb
+	})
+	return mc
 }
 
 // Zeros returns a ptr to a m x n matrix with zero-initialised elements
-func Zeros(m, n int) *Matrix {
-	return &Matrix{m, n, make([]float64, m * n)}
-}
\ No newline at end of file
+func Zeros(m, n int) *Dense {
+	return &Dense{rows: m, cols: n, stride: n, data: make([]float64, m*n)}
+}
@@ -15,7 +15,7 @@ func ExampleAdd() {
 	mb := New([][]float64{{5,6},
 		                  {7,8}})
 	mc := Add(ma, mb)
-	fmt.Printf("Rows: %d\nCols: %d\nData: %v", mc.m, mc.n, mc.data)
+	fmt.Printf("Rows: %d\nCols: %d\nData: %v", mc.rows, mc.cols, mc.data)
 	// Output:
 	// Rows: 2
 	// Cols: 2
@@ -29,7 +29,7 @@ func ExampleDot() {
 	mb := New([][]float64{{1,2,3},
 						  {4,5,6}})
 	mc := Dot(ma, mb)
-	fmt.Printf("Rows: %d\nCols: %d\nData: %v", mc.m, mc.n, mc.data)
+	fmt.Printf("Rows: %d\nCols: %d\nData: %v", mc.rows, mc.cols, mc.data)
 	// Output:
 	// Rows: 3
 	// Cols: 3
@@ -38,7 +38,7 @@ func ExampleDot() {
 
 func ExampleOnes() {
 	ma := Ones(3,2)
-	fmt.Printf("Rows: %d\nCols: %d\nData: %v", ma.m, ma.n, ma.data)
+	fmt.Printf("Rows: %d\nCols: %d\nData: %v", ma.rows, ma.cols, ma.data)
 	// Output:
 	// Rows: 3
 	// Cols: 2
@@ -51,7 +51,7 @@ func ExampleSub() {
 	mb := New([][]float64{{5,6},
 		                  {7,8}})
 	mc := Sub(ma, mb)
-	fmt.Printf("Rows: %d\nCols: %d\nData: %v", mc.m, mc.n, mc.data)
+	fmt.Printf("Rows: %d\nCols: %d\nData: %v", mc.rows, mc.cols, mc.data)
 	// Output:
 	// Rows: 2
 	// Cols: 2
@@ -61,15 +61,17 @@ func ExampleSub() {
 func ExampleTranspose() {
 	ma := New([][]float64{{1,2,3},
 						  {4,5,6}})
-	mb := Transpose(ma)
-	fmt.Printf("Rows: %d\nCols: %d\nData: %v", mb.m, mb.n, mb.data)
+	mt := ma.T()
+	r, c := mt.Dims()
+	fmt.Printf("Rows: %d\nCols: %d\nData: [%v %v %v %v %v %v]", r, c,
+		mt.At(0,0), mt.At(0,1), mt.At(1,0), mt.At(1,1), mt.At(2,0), mt.At(2,1))
 	// Output:
 	// Rows: 3
 	// Cols: 2
 	// Data: [1 4 2 5 3 6]
 }
 
-func ExampleValueAt() {
+func ExampleDense_ValueAt() {
 	row, col := 0, 1
 	ma := New([][]float64{{1,2,3},
 						  {4,5,6}})
@@ -81,7 +83,7 @@ func ExampleValueAt() {
 	// Value: 2
 }
 
-func ExampleValueAtIndex() {
+func ExampleDense_ValueAtIndex() {
 	index := 4
 	ma := New([][]float64{{1,2,3},
 						  {4,5,6}})
@@ -94,7 +96,7 @@ func ExampleValueAtIndex() {
 
 func ExampleZeros() {
 	ma := Zeros(3,2)
-	fmt.Printf("Rows: %d\nCols: %d\nData: %v", ma.m, ma.n, ma.data)
+	fmt.Printf("Rows: %d\nCols: %d\nData: %v", ma.rows, ma.cols, ma.data)
 	// Output:
 	// Rows: 3
 	// Cols: 2
@@ -136,6 +138,6 @@ func BenchmarkTranspose(b *testing.B) {
 	ma := Randn(1000, 1000)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		Transpose(ma)
+		ma.T()
 	}
 }
\ No newline at end of file
@@ -0,0 +1,68 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+import "sync"
+
+// parallelThreshold is the minimum amount of work (typically rows*cols)
+// below which parallelRows runs sequentially on the calling goroutine -
+// dispatching goroutines for small matrices costs more in scheduling
+// than it saves.
+const parallelThreshold = 4096
+
+// numWorkers is the number of goroutines Add, Sub, Mul, Scale, Sigmoid
+// and Dot split their outer row loop across. It defaults to 1, i.e.
+// sequential execution.
+var numWorkers = 1
+
+// SetParallel sets the number of goroutines that subsequent Add, Sub,
+// Mul, Scale, Sigmoid and Dot calls split their outer row loop across.
+// n < 1 is treated as 1 (sequential). It is not safe to call
+// SetParallel concurrently with other gomat operations.
+func SetParallel(n int) {
+	if n < 1 {
+		n = 1
+	}
+	numWorkers = n
+}
+
+// parallelRows runs fn(i) for every i in [0, rows), splitting the range
+// into at most numWorkers disjoint chunks run on separate goroutines as
+// parallelChunks does; otherwise it runs sequentially on the caller.
+func parallelRows(rows, work int, fn func(i int)) {
+	parallelChunks(rows, work, func(start, end int) {
+		for i := start; i < end; i++ {
+			fn(i)
+		}
+	})
+}
+
+// parallelChunks splits [0, rows) into at most numWorkers disjoint,
+// contiguous ranges of ceil(rows/numWorkers) rows and runs fn(start, end)
+// for each on its own goroutine, when the problem is large enough
+// (work >= parallelThreshold) to be worth the dispatch overhead and
+// there are at least numWorkers rows; otherwise it runs fn(0, rows)
+// sequentially on the caller. Each worker owns a disjoint row range, so fn needs no
+// locking.
+func parallelChunks(rows, work int, fn func(start, end int)) {
+	if numWorkers <= 1 || work < parallelThreshold || rows < numWorkers {
+		fn(0, rows)
+		return
+	}
+	var wg sync.WaitGroup
+	chunk := (rows + numWorkers - 1) / numWorkers
+	for start := 0; start < rows; start += chunk {
+		end := start + chunk
+		if end > rows {
+			end = rows
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,37 @@
+// Copyright 2018 Armin Catovic. All rights reserved.
+// Use of this source code is governed by a MIT license
+// that can be found in the LICENSE file.
+
+package gomat
+
+import "testing"
+
+// TestSetParallelMatchesSequential checks that splitting the outer row
+// loop across goroutines doesn't change the result, for a matrix large
+// enough to clear parallelThreshold and actually dispatch workers.
+func TestSetParallelMatchesSequential(t *testing.T) {
+	defer SetParallel(1)
+
+	const n = 128 // n*n > parallelThreshold
+	ma := Randn(n, n)
+	mb := Randn(n, n)
+
+	SetParallel(1)
+	wantAdd := Add(ma, mb)
+	wantDot := Dot(ma, mb)
+
+	SetParallel(4)
+	gotAdd := Add(ma, mb)
+	gotDot := Dot(ma, mb)
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if gotAdd.At(i, j) != wantAdd.At(i, j) {
+				t.Fatalf("Add: At(%d, %d) = %v, want %v", i, j, gotAdd.At(i, j), wantAdd.At(i, j))
+			}
+			if gotDot.At(i, j) != wantDot.At(i, j) {
+				t.Fatalf("Dot: At(%d, %d) = %v, want %v", i, j, gotDot.At(i, j), wantDot.At(i, j))
+			}
+		}
+	}
+}